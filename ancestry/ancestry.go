@@ -0,0 +1,144 @@
+// Package ancestry holds the genealogy data model that every source format
+// parser produces and that render consumes. It is deliberately format
+// agnostic: gramps, gedcom, and json each translate their own file format
+// into these types.
+package ancestry
+
+type Handle string
+
+type entity struct {
+	Id      string
+	Changed int
+	Handle  Handle
+	// Private records an explicit privacy flag from the source file, e.g.
+	// Gramps' priv attribute. See the privacy package for how this combines
+	// with other rules to decide what actually gets redacted.
+	Private bool
+}
+
+type Entity interface {
+	GetId() string
+	GetChanged() int
+	GetHandle() Handle
+	GetTypeName() string
+	GetLabel() string
+	GetPrivate() bool
+}
+
+func (entity entity) GetId() string {
+	return entity.Id
+}
+
+func (entity entity) GetChanged() int {
+	return entity.Changed
+}
+
+func (entity entity) GetHandle() Handle {
+	return entity.Handle
+}
+
+func (entity entity) GetLabel() string {
+	return entity.GetId()
+}
+
+func (entity entity) GetPrivate() bool {
+	return entity.Private
+}
+
+type Event struct {
+	entity
+	// Place is the handle of the place this event occurred at, or nil if
+	// none is recorded. Parsers that cannot resolve it leave it nil.
+	Place *Handle
+	// Person is the handle of this event's primary participant, or nil if
+	// none is recorded. The privacy package cascades that person's privacy
+	// onto the event.
+	Person *Handle
+}
+
+func (_ Event) GetTypeName() string {
+	return "event"
+}
+
+// NewEvent builds an Event, for use by Parser implementations.
+func NewEvent(id string, changed int, handle Handle, private bool) Event {
+	return Event{entity: entity{Id: id, Changed: changed, Handle: handle, Private: private}}
+}
+
+type Person struct {
+	entity
+	FamilyName     string
+	IndividualName string
+	// BirthYear is the year of the person's birth event, or 0 if it is
+	// unknown or the parser could not resolve it. It exists for the
+	// privacy package's living-person heuristic.
+	BirthYear int
+	// Deceased records whether a death event is known for this person.
+	Deceased bool
+	// FamiliesAsParent holds the handles of the families this person is a
+	// parent in.
+	FamiliesAsParent []Handle
+	// FamiliesAsChild holds the handles of the families this person is a
+	// child in. Parsers that only record family membership the other way
+	// around (i.e. a family's list of children) resolve this as the
+	// reverse edge of that relationship.
+	FamiliesAsChild []Handle
+}
+
+func (_ Person) GetTypeName() string {
+	return "person"
+}
+
+func (person Person) GetLabel() string {
+	return person.FamilyName + ", " + person.IndividualName
+}
+
+// NewPerson builds a Person, for use by Parser implementations.
+func NewPerson(id string, changed int, handle Handle, private bool, familyName string, individualName string, birthYear int, deceased bool) Person {
+	return Person{
+		entity:         entity{Id: id, Changed: changed, Handle: handle, Private: private},
+		FamilyName:     familyName,
+		IndividualName: individualName,
+		BirthYear:      birthYear,
+		Deceased:       deceased,
+	}
+}
+
+type Family struct {
+	entity
+	// Father and Mother are the handles of this family's parents, or nil
+	// if unknown.
+	Father *Handle
+	Mother *Handle
+	// Children holds the handles of this family's children.
+	Children []Handle
+}
+
+func (_ Family) GetTypeName() string {
+	return "family"
+}
+
+// NewFamily builds a Family, for use by Parser implementations.
+func NewFamily(id string, changed int, handle Handle, private bool) Family {
+	return Family{entity: entity{Id: id, Changed: changed, Handle: handle, Private: private}}
+}
+
+type Place struct {
+	entity
+}
+
+func (_ Place) GetTypeName() string {
+	return "place"
+}
+
+// NewPlace builds a Place, for use by Parser implementations.
+func NewPlace(id string, changed int, handle Handle, private bool) Place {
+	return Place{entity{Id: id, Changed: changed, Handle: handle, Private: private}}
+}
+
+type Ancestry struct {
+	People   []Person
+	Events   []Event
+	Places   []Place
+	Families []Family
+}
@@ -0,0 +1,31 @@
+package ancestry
+
+import (
+	"fmt"
+	"io"
+)
+
+// Parser turns a source format's serialization of genealogy data into an
+// Ancestry. Implementations register themselves with RegisterParser under
+// the format name the CLI's --format flag expects, typically from an init()
+// function so importing the package for its side effect is enough.
+type Parser interface {
+	Parse(io.Reader) (*Ancestry, error)
+}
+
+var parsers = map[string]Parser{}
+
+// RegisterParser makes a Parser available under format, e.g. "gramps" or
+// "gedcom". It is meant to be called from an implementation's init().
+func RegisterParser(format string, parser Parser) {
+	parsers[format] = parser
+}
+
+// GetParser looks up the Parser registered for format.
+func GetParser(format string) (Parser, error) {
+	parser, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("ancestry: no parser registered for format %q", format)
+	}
+	return parser, nil
+}
@@ -0,0 +1,169 @@
+// Package gedcom parses GEDCOM 5.5.1 files into an ancestry.Ancestry.
+package gedcom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bartfeenstra/betty/ancestry"
+)
+
+func init() {
+	ancestry.RegisterParser("gedcom", Parser{})
+}
+
+// record is a single GEDCOM line, with its nested lines attached as subs.
+type record struct {
+	xref  string
+	tag   string
+	value string
+	subs  []*record
+}
+
+func (rec *record) sub(tag string) *record {
+	for _, sub := range rec.subs {
+		if sub.tag == tag {
+			return sub
+		}
+	}
+	return nil
+}
+
+// children returns every direct sub-record with the given tag, for
+// repeatable tags such as FAMS and CHIL.
+func (rec *record) children(tag string) []*record {
+	var matches []*record
+	for _, sub := range rec.subs {
+		if sub.tag == tag {
+			matches = append(matches, sub)
+		}
+	}
+	return matches
+}
+
+// Parser implements ancestry.Parser for GEDCOM 5.5.1.
+type Parser struct{}
+
+func (Parser) Parse(r io.Reader) (*ancestry.Ancestry, error) {
+	records, err := parseRecords(r)
+	if err != nil {
+		return nil, err
+	}
+	result := &ancestry.Ancestry{}
+	for _, record := range records {
+		switch record.tag {
+		case "INDI":
+			result.People = append(result.People, toPerson(record))
+		case "FAM":
+			result.Families = append(result.Families, toFamily(record))
+		}
+	}
+	return result, nil
+}
+
+// parseRecords reads GEDCOM's flat, level-prefixed lines and nests them into
+// a tree per the level numbers, e.g. a "1 NAME" line becomes a sub-record of
+// the preceding "0 @I1@ INDI" record.
+func parseRecords(r io.Reader) ([]*record, error) {
+	var roots []*record
+	var stack []*record
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		level, rec, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		for len(stack) > level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, rec)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.subs = append(parent.subs, rec)
+		}
+		stack = append(stack, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+func parseLine(line string) (int, *record, error) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return 0, nil, fmt.Errorf("gedcom: malformed line %q", line)
+	}
+	level, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, nil, fmt.Errorf("gedcom: invalid level in line %q", line)
+	}
+	rec := &record{}
+	if strings.HasPrefix(parts[1], "@") {
+		rec.xref = strings.Trim(parts[1], "@")
+		if len(parts) > 2 {
+			rec.tag = parts[2]
+		}
+	} else {
+		rec.tag = parts[1]
+		if len(parts) > 2 {
+			rec.value = parts[2]
+		}
+	}
+	return level, rec, nil
+}
+
+func toPerson(record *record) ancestry.Person {
+	familyName, individualName := "", ""
+	if name := record.sub("NAME"); name != nil {
+		individualName, familyName = splitName(name.value)
+	}
+	person := ancestry.NewPerson(record.xref, 0, ancestry.Handle(record.xref), false, familyName, individualName, 0, false)
+	for _, famc := range record.children("FAMC") {
+		person.FamiliesAsChild = append(person.FamiliesAsChild, ancestry.Handle(trimXref(famc.value)))
+	}
+	for _, fams := range record.children("FAMS") {
+		person.FamiliesAsParent = append(person.FamiliesAsParent, ancestry.Handle(trimXref(fams.value)))
+	}
+	return person
+}
+
+func toFamily(record *record) ancestry.Family {
+	family := ancestry.NewFamily(record.xref, 0, ancestry.Handle(record.xref), false)
+	if husb := record.sub("HUSB"); husb != nil {
+		father := ancestry.Handle(trimXref(husb.value))
+		family.Father = &father
+	}
+	if wife := record.sub("WIFE"); wife != nil {
+		mother := ancestry.Handle(trimXref(wife.value))
+		family.Mother = &mother
+	}
+	for _, chil := range record.children("CHIL") {
+		family.Children = append(family.Children, ancestry.Handle(trimXref(chil.value)))
+	}
+	return family
+}
+
+// splitName splits a GEDCOM "Given /Surname/" NAME value into its parts.
+func splitName(value string) (individualName string, familyName string) {
+	parts := strings.SplitN(value, "/", 3)
+	individualName = strings.TrimSpace(parts[0])
+	if len(parts) > 1 {
+		familyName = strings.TrimSpace(parts[1])
+	}
+	return
+}
+
+// trimXref strips the "@...@" delimiters GEDCOM wraps cross-reference
+// values in, e.g. "@F1@" becomes "F1".
+func trimXref(value string) string {
+	return strings.Trim(value, "@")
+}
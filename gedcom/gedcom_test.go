@@ -0,0 +1,92 @@
+package gedcom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bartfeenstra/betty/ancestry"
+)
+
+const sampleGEDCOM = `0 @I1@ INDI
+1 NAME John /Doe/
+1 FAMS @F1@
+0 @I2@ INDI
+1 NAME Jane /Doe/
+1 FAMS @F1@
+0 @I3@ INDI
+1 NAME Jack /Doe/
+1 FAMC @F1@
+0 @F1@ FAM
+1 HUSB @I1@
+1 WIFE @I2@
+1 CHIL @I3@
+`
+
+func TestParserParse(t *testing.T) {
+	anc, err := Parser{}.Parse(strings.NewReader(sampleGEDCOM))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+
+	if len(anc.People) != 3 {
+		t.Fatalf("got %d people, want 3", len(anc.People))
+	}
+	if len(anc.Families) != 1 {
+		t.Fatalf("got %d families, want 1", len(anc.Families))
+	}
+
+	john := anc.People[0]
+	if john.GetId() != "I1" || john.IndividualName != "John" || john.FamilyName != "Doe" {
+		t.Errorf("got person %+v, want id I1, name John Doe", john)
+	}
+	if len(john.FamiliesAsParent) != 1 || john.FamiliesAsParent[0] != ancestry.Handle("F1") {
+		t.Errorf("got FamiliesAsParent %v, want [F1]", john.FamiliesAsParent)
+	}
+
+	jack := anc.People[2]
+	if len(jack.FamiliesAsChild) != 1 || jack.FamiliesAsChild[0] != ancestry.Handle("F1") {
+		t.Errorf("got FamiliesAsChild %v, want [F1]", jack.FamiliesAsChild)
+	}
+
+	family := anc.Families[0]
+	if family.GetId() != "F1" {
+		t.Fatalf("got family id %q, want F1", family.GetId())
+	}
+	if family.Father == nil || *family.Father != ancestry.Handle("I1") {
+		t.Errorf("got Father %v, want I1", family.Father)
+	}
+	if family.Mother == nil || *family.Mother != ancestry.Handle("I2") {
+		t.Errorf("got Mother %v, want I2", family.Mother)
+	}
+	if len(family.Children) != 1 || family.Children[0] != ancestry.Handle("I3") {
+		t.Errorf("got Children %v, want [I3]", family.Children)
+	}
+}
+
+func TestParseRecordsNestsByLevel(t *testing.T) {
+	records, err := parseRecords(strings.NewReader(sampleGEDCOM))
+	if err != nil {
+		t.Fatalf("parseRecords() returned an unexpected error: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d root records, want 4", len(records))
+	}
+
+	john := records[0]
+	if john.xref != "I1" || john.tag != "INDI" {
+		t.Fatalf("got root record %+v, want xref I1, tag INDI", john)
+	}
+	if john.sub("NAME") == nil || john.sub("NAME").value != "John /Doe/" {
+		t.Errorf("got NAME sub %+v, want value %q", john.sub("NAME"), "John /Doe/")
+	}
+	if len(john.children("FAMS")) != 1 {
+		t.Errorf("got %d FAMS children, want 1", len(john.children("FAMS")))
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	individualName, familyName := splitName("John /Doe/")
+	if individualName != "John" || familyName != "Doe" {
+		t.Errorf("splitName() = (%q, %q), want (%q, %q)", individualName, familyName, "John", "Doe")
+	}
+}
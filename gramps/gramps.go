@@ -1,16 +1,25 @@
+// Package gramps parses Gramps XML exports into an ancestry.Ancestry.
 package gramps
 
 import (
 	"encoding/xml"
+	"github.com/bartfeenstra/betty/ancestry"
+	"io"
 	"io/ioutil"
+	"strconv"
 )
 
+func init() {
+	ancestry.RegisterParser("gramps", Parser{})
+}
+
 type Handle string
 
 type entity struct {
 	Id      string `xml:"id,attr"`
 	Changed int    `xml:"change,attr"`
 	Handle  Handle `xml:"handle,attr"`
+	Private bool   `xml:"priv,attr"`
 }
 
 type Entity interface {
@@ -18,6 +27,8 @@ type Entity interface {
 	GetChanged() int
 	GetHandle() Handle
 	GetTypeName() string
+	GetLabel() string
+	GetPrivate() bool
 }
 
 func (entity entity) GetId() string {
@@ -32,29 +43,83 @@ func (entity entity) GetHandle() Handle {
 	return entity.Handle
 }
 
+func (entity entity) GetLabel() string {
+	return entity.GetId()
+}
+
+func (entity entity) GetPrivate() bool {
+	return entity.Private
+}
+
 type Event struct {
 	entity
+	Type     string    `xml:"type"`
+	DateVal  dateVal   `xml:"dateval"`
+	PlaceRef *hlinkRef `xml:"placeref"`
+}
+
+// hlinkRef is a bare reference to another entity by handle, used by
+// elements that carry no further attributes of their own, such as
+// <father>, <mother>, <childref>, and <placeref>.
+type hlinkRef struct {
+	HLink Handle `xml:"hlink,attr"`
 }
 
-func (event Event) GetTypeName() string {
+func (_ Event) GetTypeName() string {
 	return "event"
 }
 
+// dateVal is Gramps' representation of a (possibly partial) date, e.g.
+// "1990-01-02" or just "1990".
+type dateVal struct {
+	Val string `xml:"val,attr"`
+}
+
+// year extracts the four-digit year from a dateval's val attribute, or 0 if
+// it cannot be parsed.
+func (dateVal dateVal) year() int {
+	if len(dateVal.Val) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(dateVal.Val[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
 type Person struct {
 	entity
-	FamilyName     string `xml:"name>surname"`
-	IndividualName string `xml:"name>first"`
+	FamilyName     string     `xml:"name>surname"`
+	IndividualName string     `xml:"name>first"`
+	EventRefs      []eventRef `xml:"eventref"`
+	// ParentIn holds the families this person is a parent in.
+	ParentIn []hlinkRef `xml:"parentin"`
 }
 
-func (event Person) GetTypeName() string {
+func (_ Person) GetTypeName() string {
 	return "person"
 }
 
+func (person Person) GetLabel() string {
+	return person.FamilyName + ", " + person.IndividualName
+}
+
+// eventRef is a person's reference to one of the file's events, e.g. their
+// birth or death.
+type eventRef struct {
+	HLink Handle `xml:"hlink,attr"`
+	Role  string `xml:"role,attr"`
+}
+
 type Family struct {
 	entity
+	Father    *hlinkRef  `xml:"father"`
+	Mother    *hlinkRef  `xml:"mother"`
+	ChildRefs []hlinkRef `xml:"childref"`
 }
 
-func (event Family) GetTypeName() string {
+func (_ Family) GetTypeName() string {
 	return "family"
 }
 
@@ -62,7 +127,7 @@ type Place struct {
 	entity
 }
 
-func (event Place) GetTypeName() string {
+func (_ Place) GetTypeName() string {
 	return "place"
 }
 
@@ -73,12 +138,160 @@ type Ancestry struct {
 	Families []Family `xml:"families>family"`
 }
 
-func Parse(file_path string) (*Ancestry, error) {
-	grampsBytes, err := ioutil.ReadFile(file_path)
+// Parser implements ancestry.Parser for Gramps XML.
+type Parser struct{}
+
+func (Parser) Parse(r io.Reader) (*ancestry.Ancestry, error) {
+	grampsBytes, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
-	var ancestry Ancestry
-	err = xml.Unmarshal(grampsBytes, &ancestry)
-	return &ancestry, nil
+	var grampsAncestry Ancestry
+	err = xml.Unmarshal(grampsBytes, &grampsAncestry)
+	if err != nil {
+		return nil, err
+	}
+	return toAncestry(&grampsAncestry), nil
+}
+
+// Index resolves a parsed Ancestry's events and relationships by handle,
+// so toAncestry can look them up in O(1) instead of scanning the whole
+// Ancestry for every reference. It also holds reverse edges, such as which
+// families a person is a child in, that the XML itself only records in the
+// other direction. Only events are indexed by handle; people, places, and
+// families are not.
+type Index struct {
+	events map[Handle]Event
+	// familiesAsChild is the reverse edge of Family.ChildRefs: the handles
+	// of the families a person (by handle) is a child in.
+	familiesAsChild map[Handle][]Handle
+	// eventPrimaryPerson is the reverse edge of Person.EventRefs: the handle
+	// of the person holding the "Primary" role in a given event, if any.
+	eventPrimaryPerson map[Handle]Handle
+}
+
+func newIndex(grampsAncestry *Ancestry) *Index {
+	index := &Index{
+		events:             make(map[Handle]Event, len(grampsAncestry.Events)),
+		familiesAsChild:    map[Handle][]Handle{},
+		eventPrimaryPerson: map[Handle]Handle{},
+	}
+	for _, event := range grampsAncestry.Events {
+		index.events[event.GetHandle()] = event
+	}
+	for _, family := range grampsAncestry.Families {
+		for _, childRef := range family.ChildRefs {
+			index.familiesAsChild[childRef.HLink] = append(index.familiesAsChild[childRef.HLink], family.GetHandle())
+		}
+	}
+	for _, person := range grampsAncestry.People {
+		for _, ref := range person.EventRefs {
+			if ref.Role == "Primary" {
+				index.eventPrimaryPerson[ref.HLink] = person.GetHandle()
+			}
+		}
+	}
+	return index
+}
+
+func toAncestry(grampsAncestry *Ancestry) *ancestry.Ancestry {
+	index := newIndex(grampsAncestry)
+
+	people := make([]ancestry.Person, 0, len(grampsAncestry.People))
+	for _, person := range grampsAncestry.People {
+		birthYear, deceased := birthYearAndDeceased(person, index)
+		converted := ancestry.NewPerson(person.GetId(), person.GetChanged(), ancestry.Handle(person.GetHandle()), person.GetPrivate(), person.FamilyName, person.IndividualName, birthYear, deceased)
+		converted.FamiliesAsParent = toHandles(person.ParentIn)
+		converted.FamiliesAsChild = toAncestryHandles(index.familiesAsChild[person.GetHandle()])
+		people = append(people, converted)
+	}
+	events := make([]ancestry.Event, 0, len(grampsAncestry.Events))
+	for _, event := range grampsAncestry.Events {
+		converted := ancestry.NewEvent(event.GetId(), event.GetChanged(), ancestry.Handle(event.GetHandle()), event.GetPrivate())
+		converted.Place = toHandle(event.PlaceRef)
+		converted.Person = index.primaryPerson(event.GetHandle())
+		events = append(events, converted)
+	}
+	places := make([]ancestry.Place, 0, len(grampsAncestry.Places))
+	for _, place := range grampsAncestry.Places {
+		places = append(places, ancestry.NewPlace(place.GetId(), place.GetChanged(), ancestry.Handle(place.GetHandle()), place.GetPrivate()))
+	}
+	families := make([]ancestry.Family, 0, len(grampsAncestry.Families))
+	for _, family := range grampsAncestry.Families {
+		converted := ancestry.NewFamily(family.GetId(), family.GetChanged(), ancestry.Handle(family.GetHandle()), family.GetPrivate())
+		converted.Father = toHandle(family.Father)
+		converted.Mother = toHandle(family.Mother)
+		converted.Children = toHandles(family.ChildRefs)
+		families = append(families, converted)
+	}
+	return &ancestry.Ancestry{
+		People:   people,
+		Events:   events,
+		Places:   places,
+		Families: families,
+	}
+}
+
+// birthYearAndDeceased resolves person's birth year and whether a death
+// event is known for them, by following their eventref elements into
+// index's events. This is a narrow, local lookup for the privacy package's
+// living-person heuristic; it does not attempt to resolve any other
+// relationships.
+func birthYearAndDeceased(person Person, index *Index) (int, bool) {
+	var birthYear int
+	var deceased bool
+	for _, ref := range person.EventRefs {
+		event, ok := index.events[ref.HLink]
+		if !ok {
+			continue
+		}
+		switch event.Type {
+		case "Birth":
+			birthYear = event.DateVal.year()
+		case "Death":
+			deceased = true
+		}
+	}
+	return birthYear, deceased
+}
+
+// primaryPerson resolves the handle of the person holding the "Primary" role
+// in the event at eventHandle, or nil if none is recorded.
+func (index *Index) primaryPerson(eventHandle Handle) *ancestry.Handle {
+	person, ok := index.eventPrimaryPerson[eventHandle]
+	if !ok {
+		return nil
+	}
+	handle := ancestry.Handle(person)
+	return &handle
+}
+
+// toHandle converts an optional Gramps hlink reference into the neutral
+// ancestry package's Handle type.
+func toHandle(ref *hlinkRef) *ancestry.Handle {
+	if ref == nil {
+		return nil
+	}
+	handle := ancestry.Handle(ref.HLink)
+	return &handle
+}
+
+// toHandles converts a list of Gramps hlink references into the neutral
+// ancestry package's Handle type.
+func toHandles(refs []hlinkRef) []ancestry.Handle {
+	handles := make([]ancestry.Handle, 0, len(refs))
+	for _, ref := range refs {
+		handles = append(handles, ancestry.Handle(ref.HLink))
+	}
+	return handles
+}
+
+// toAncestryHandles converts a list of Gramps Handles into the neutral
+// ancestry package's Handle type.
+func toAncestryHandles(handles []Handle) []ancestry.Handle {
+	converted := make([]ancestry.Handle, 0, len(handles))
+	for _, handle := range handles {
+		converted = append(converted, ancestry.Handle(handle))
+	}
+	return converted
 }
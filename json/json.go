@@ -0,0 +1,25 @@
+// Package json parses Betty's own JSON serialization of an ancestry.Ancestry.
+package json
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/bartfeenstra/betty/ancestry"
+)
+
+func init() {
+	ancestry.RegisterParser("json", Parser{})
+}
+
+// Parser implements ancestry.Parser for JSON.
+type Parser struct{}
+
+func (Parser) Parse(r io.Reader) (*ancestry.Ancestry, error) {
+	var result ancestry.Ancestry
+	err := json.NewDecoder(r).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -2,13 +2,38 @@ package main
 
 import (
 	"fmt"
-	"github.com/bartfeenstra/betty/gramps"
+	"github.com/bartfeenstra/betty/ancestry"
+	_ "github.com/bartfeenstra/betty/gedcom"
+	_ "github.com/bartfeenstra/betty/gramps"
+	_ "github.com/bartfeenstra/betty/json"
+	"github.com/bartfeenstra/betty/privacy"
+	"github.com/bartfeenstra/betty/render"
 	"github.com/jessevdk/go-flags"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 type Options struct {
-	FilePath string `long:"filepath" required:"true"`
+	InputFilePath       string `short:"i" long:"input" required:"true" description:"The path to the genealogy file to render, or - to read from stdin."`
+	Format              string `short:"f" long:"format" description:"The input format: gramps, gedcom, or json. Auto-detected from --input's file extension when omitted."`
+	OutputDirectoryPath string `short:"o" long:"output" required:"true" description:"The path to the output directory."`
+	ThemePath           string `long:"theme" description:"The path to a directory of HTML templates to render with. Defaults to Betty's built-in theme."`
+	BaseURL             string `long:"base-url" description:"The public URL the rendered site will be served from."`
+	SiteTitle           string `long:"title" default:"Betty" description:"The title of the rendered site."`
+	Incremental         bool   `long:"incremental" description:"Reuse the output directory from a previous render, skipping entities that haven't changed."`
+	PrivacyPolicyPath   string `long:"privacy-policy" description:"The path to a YAML privacy policy file. When omitted, privacy filtering is disabled, unless --redact-private is given, in which case the default policy applies."`
+	RedactPrivate       bool   `long:"redact-private" description:"Render a redacted stub page for private entities, instead of omitting them entirely."`
+}
+
+// formatsByExtension maps recognized file extensions to the parser format
+// they imply, for when --format is omitted.
+var formatsByExtension = map[string]string{
+	".gramps": "gramps",
+	".xml":    "gramps",
+	".ged":    "gedcom",
+	".gedcom": "gedcom",
+	".json":   "json",
 }
 
 func ExitBetty(err error) {
@@ -23,9 +48,75 @@ func main() {
 	if err != nil {
 		ExitBetty(err)
 	}
-	ancestry, err := gramps.Parse(options.FilePath)
+
+	format := options.Format
+	if format == "" {
+		format, err = detectFormat(options.InputFilePath)
+		if err != nil {
+			ExitBetty(err)
+		}
+	}
+	formatParser, err := ancestry.GetParser(format)
 	if err != nil {
 		ExitBetty(err)
 	}
-	fmt.Printf("%#v", ancestry)
+
+	input, err := openInput(options.InputFilePath)
+	if err != nil {
+		ExitBetty(err)
+	}
+	if input != os.Stdin {
+		defer input.Close()
+	}
+	parsedAncestry, err := formatParser.Parse(input)
+	if err != nil {
+		ExitBetty(err)
+	}
+
+	mode := render.ModeFresh
+	if options.Incremental {
+		mode = render.ModeIncremental
+	}
+	var privacyPolicy *privacy.Policy
+	if options.PrivacyPolicyPath != "" {
+		policy, err := privacy.LoadPolicy(options.PrivacyPolicyPath)
+		if err != nil {
+			ExitBetty(err)
+		}
+		policy.Redact = policy.Redact || options.RedactPrivate
+		privacyPolicy = &policy
+	} else if options.RedactPrivate {
+		policy := privacy.DefaultPolicy()
+		policy.Redact = true
+		privacyPolicy = &policy
+	}
+	renderOptions := render.Options{
+		ThemePath: options.ThemePath,
+		BaseURL:   options.BaseURL,
+		SiteTitle: options.SiteTitle,
+		Mode:      mode,
+		Privacy:   privacyPolicy,
+	}
+	err = render.Render(parsedAncestry, options.OutputDirectoryPath, renderOptions)
+	if err != nil {
+		ExitBetty(err)
+	}
+	fmt.Printf("The genealogy data from %s has been rendered and placed into %s.\n", options.InputFilePath, options.OutputDirectoryPath)
+}
+
+// openInput opens inputFilePath for reading, or os.Stdin when it is "-".
+func openInput(inputFilePath string) (*os.File, error) {
+	if inputFilePath == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(inputFilePath)
+}
+
+func detectFormat(inputFilePath string) (string, error) {
+	extension := strings.ToLower(filepath.Ext(inputFilePath))
+	format, ok := formatsByExtension[extension]
+	if !ok {
+		return "", fmt.Errorf("cannot detect a format from %q; pass --format explicitly", inputFilePath)
+	}
+	return format, nil
 }
@@ -0,0 +1,50 @@
+package privacy
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// policyFile is the shape of the YAML file LoadPolicy reads. Its allow and
+// deny lists are handles or Gramps IDs.
+type policyFile struct {
+	LivingYears int      `yaml:"livingYears"`
+	Redact      bool     `yaml:"redact"`
+	Allow       []string `yaml:"allow"`
+	Deny        []string `yaml:"deny"`
+}
+
+// LoadPolicy reads a Policy from the YAML file at path, e.g.:
+//
+//	livingYears: 100
+//	redact: true
+//	allow:
+//	  - I0001
+//	deny:
+//	  - I0002
+func LoadPolicy(path string) (Policy, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+	var file policyFile
+	if err := yaml.Unmarshal(bytes, &file); err != nil {
+		return Policy{}, err
+	}
+	policy := Policy{
+		LivingYears: file.LivingYears,
+		Redact:      file.Redact,
+		Allow:       toSet(file.Allow),
+		Deny:        toSet(file.Deny),
+	}
+	return policy, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,122 @@
+// Package privacy decides which entities in an ancestry should be hidden or
+// redacted before they are rendered, so a published site does not expose
+// information about people who may still be alive.
+package privacy
+
+import (
+	"time"
+
+	"github.com/bartfeenstra/betty/ancestry"
+)
+
+// defaultLivingYears is how many years after a birth a person is presumed to
+// still be alive, absent a recorded death.
+const defaultLivingYears = 100
+
+// Policy configures how privacy is decided for an ancestry.
+type Policy struct {
+	// LivingYears is the number of years after a person's birth during
+	// which they are presumed to still be alive if no death is recorded.
+	// Zero means DefaultPolicy's default of 100.
+	LivingYears int
+	// Redact renders a redacted stub page for private entities, instead of
+	// omitting them from the output entirely.
+	Redact bool
+	// Allow overrides every other rule except Deny to mark an entity public,
+	// keyed by handle or Gramps ID.
+	Allow map[string]bool
+	// Deny overrides every other rule to mark an entity private, keyed by
+	// handle or Gramps ID.
+	Deny map[string]bool
+}
+
+// DefaultPolicy is a Policy with the default settings: persons born within
+// the last 100 years and presumed alive are private, and nothing is
+// explicitly allowed or denied. Render does not apply it on its own: with no
+// Policy configured, privacy filtering is disabled.
+func DefaultPolicy() Policy {
+	return Policy{LivingYears: defaultLivingYears}
+}
+
+func (policy Policy) livingYears() int {
+	if policy.LivingYears == 0 {
+		return defaultLivingYears
+	}
+	return policy.LivingYears
+}
+
+func (policy Policy) listed(entity ancestry.Entity) (private bool, explicit bool) {
+	if policy.Deny[string(entity.GetHandle())] || policy.Deny[entity.GetId()] {
+		return true, true
+	}
+	if policy.Allow[string(entity.GetHandle())] || policy.Allow[entity.GetId()] {
+		return false, true
+	}
+	return false, false
+}
+
+// IsPrivate reports whether entity should be considered private under
+// policy. known holds the privacy verdicts already decided for other
+// entities in the same ancestry, so a family or event can cascade the
+// privacy of the people it involves; pass an empty map when no other
+// verdicts are available yet.
+func IsPrivate(policy Policy, entity ancestry.Entity, known map[ancestry.Handle]bool) bool {
+	if private, explicit := policy.listed(entity); explicit {
+		return private
+	}
+	if entity.GetPrivate() {
+		return true
+	}
+	switch typed := entity.(type) {
+	case ancestry.Person:
+		return isLivingPerson(policy, typed)
+	case ancestry.Family:
+		return isHandlePrivate(known, typed.Father) || isHandlePrivate(known, typed.Mother)
+	case ancestry.Event:
+		return isHandlePrivate(known, typed.Person)
+	}
+	return false
+}
+
+func isLivingPerson(policy Policy, person ancestry.Person) bool {
+	if person.Deceased || person.BirthYear == 0 {
+		return false
+	}
+	return time.Now().Year()-person.BirthYear < policy.livingYears()
+}
+
+// isHandlePrivate reports whether handle is known to refer to a private
+// entity, for cascading a family's or event's privacy from the people they
+// involve. It returns false for a nil handle, i.e. an unresolved reference.
+func isHandlePrivate(known map[ancestry.Handle]bool, handle *ancestry.Handle) bool {
+	return handle != nil && known[*handle]
+}
+
+// Decide evaluates policy against every entity in anc, returning the set of
+// handles that are private. People are decided first, so families and
+// events can cascade privacy from the parents and primary participants they
+// reference.
+func Decide(policy Policy, anc *ancestry.Ancestry) map[ancestry.Handle]bool {
+	private := map[ancestry.Handle]bool{}
+	for _, person := range anc.People {
+		if IsPrivate(policy, person, private) {
+			private[person.GetHandle()] = true
+		}
+	}
+	for _, event := range anc.Events {
+		if IsPrivate(policy, event, private) {
+			private[event.GetHandle()] = true
+		}
+	}
+	for _, place := range anc.Places {
+		if IsPrivate(policy, place, private) {
+			private[place.GetHandle()] = true
+		}
+	}
+	for _, family := range anc.Families {
+		if IsPrivate(policy, family, private) {
+			private[family.GetHandle()] = true
+		}
+	}
+	return private
+}
@@ -0,0 +1,126 @@
+package privacy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bartfeenstra/betty/ancestry"
+)
+
+func livingYearsAgo(years int) int {
+	return time.Now().Year() - years
+}
+
+func TestIsLivingPersonHeuristic(t *testing.T) {
+	policy := DefaultPolicy()
+	tests := []struct {
+		name   string
+		person ancestry.Person
+		want   bool
+	}{
+		{
+			"recently born, no recorded death, is private",
+			ancestry.NewPerson("I1", 0, "I1", false, "Doe", "John", livingYearsAgo(30), false),
+			true,
+		},
+		{
+			"recently born but deceased, is public",
+			ancestry.NewPerson("I2", 0, "I2", false, "Doe", "Jane", livingYearsAgo(30), true),
+			false,
+		},
+		{
+			"born over livingYears ago, is public",
+			ancestry.NewPerson("I3", 0, "I3", false, "Doe", "Jack", livingYearsAgo(200), false),
+			false,
+		},
+		{
+			"unknown birth year, is public",
+			ancestry.NewPerson("I4", 0, "I4", false, "Doe", "Jill", 0, false),
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPrivate(policy, tt.person, map[ancestry.Handle]bool{}); got != tt.want {
+				t.Errorf("IsPrivate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPrivateAllowDenyPrecedence(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.Allow = map[string]bool{"I1": true}
+	policy.Deny = map[string]bool{"I2": true}
+
+	// Allow overrides the living-person heuristic that would otherwise apply.
+	allowed := ancestry.NewPerson("I1", 0, "I1", false, "Doe", "John", livingYearsAgo(30), false)
+	if IsPrivate(policy, allowed, map[ancestry.Handle]bool{}) {
+		t.Error("IsPrivate() = true for an explicitly allowed person, want false")
+	}
+
+	// Deny overrides an otherwise-public person.
+	denied := ancestry.NewPerson("I2", 0, "I2", false, "Doe", "Jane", livingYearsAgo(200), false)
+	if !IsPrivate(policy, denied, map[ancestry.Handle]bool{}) {
+		t.Error("IsPrivate() = false for an explicitly denied person, want true")
+	}
+
+	// Deny wins when both an allow and a deny entry match the same entity.
+	conflicted := ancestry.NewPerson("I3", 0, "I3", false, "Doe", "Jack", 0, false)
+	policy.Allow["I3"] = true
+	policy.Deny["I3"] = true
+	if !IsPrivate(policy, conflicted, map[ancestry.Handle]bool{}) {
+		t.Error("IsPrivate() = false when both allow and deny list an entity, want true (deny wins)")
+	}
+}
+
+func TestDecideCascadesToFamiliesAndEvents(t *testing.T) {
+	child := ancestry.Handle("I1")
+	family := ancestry.NewFamily("F1", 0, "F1", false)
+	family.Father = &child
+
+	event := ancestry.NewEvent("E1", 0, "E1", false)
+	event.Person = &child
+
+	anc := &ancestry.Ancestry{
+		People: []ancestry.Person{
+			ancestry.NewPerson("I1", 0, "I1", false, "Doe", "John", livingYearsAgo(30), false),
+		},
+		Events:   []ancestry.Event{event},
+		Families: []ancestry.Family{family},
+	}
+
+	private := Decide(DefaultPolicy(), anc)
+
+	if !private[ancestry.Handle("I1")] {
+		t.Fatal("expected the living person to be private")
+	}
+	if !private[ancestry.Handle("F1")] {
+		t.Error("expected the family to be private because its father is private")
+	}
+	if !private[ancestry.Handle("E1")] {
+		t.Error("expected the event to be private because its primary participant is private")
+	}
+}
+
+func TestDecideDoesNotCascadeToPublicRelatives(t *testing.T) {
+	child := ancestry.Handle("I1")
+	family := ancestry.NewFamily("F1", 0, "F1", false)
+	family.Father = &child
+
+	anc := &ancestry.Ancestry{
+		People: []ancestry.Person{
+			ancestry.NewPerson("I1", 0, "I1", false, "Doe", "John", livingYearsAgo(200), false),
+		},
+		Families: []ancestry.Family{family},
+	}
+
+	private := Decide(DefaultPolicy(), anc)
+
+	if private[ancestry.Handle("I1")] {
+		t.Fatal("expected the long-deceased person to be public")
+	}
+	if private[ancestry.Handle("F1")] {
+		t.Error("expected the family to be public because its father is public")
+	}
+}
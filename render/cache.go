@@ -0,0 +1,163 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/bartfeenstra/betty/ancestry"
+	"os"
+	"path/filepath"
+)
+
+// templateVersion is bumped whenever a template change should invalidate
+// every cached render, even though no entity's data actually changed.
+const templateVersion = 1
+
+const cacheDirName = ".betty"
+const cacheFileName = "cache.json"
+const orphansFileName = "orphans.json"
+
+type cacheEntry struct {
+	Changed     int      `json:"changed"`
+	ContentHash string   `json:"contentHash"`
+	OutputPaths []string `json:"outputPaths"`
+}
+
+// Cache records, per entity handle, what was last rendered for it, so Render
+// can skip re-rendering entities whose data and templates have not changed.
+type Cache struct {
+	entries map[ancestry.Handle]cacheEntry
+}
+
+func loadCache(outputDirectoryPath string) (*Cache, error) {
+	bytes, err := os.ReadFile(cachePath(outputDirectoryPath))
+	if os.IsNotExist(err) {
+		return &Cache{entries: map[ancestry.Handle]cacheEntry{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := map[ancestry.Handle]cacheEntry{}
+	if err := json.Unmarshal(bytes, &entries); err != nil {
+		return nil, err
+	}
+	return &Cache{entries: entries}, nil
+}
+
+func (cache *Cache) save(outputDirectoryPath string) error {
+	bytes, err := json.MarshalIndent(cache.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := cachePath(outputDirectoryPath)
+	if err := CreateDirectory(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return os.WriteFile(path, bytes, 0640)
+}
+
+func cachePath(outputDirectoryPath string) string {
+	return filepath.Join(outputDirectoryPath, cacheDirName, cacheFileName)
+}
+
+func orphansPath(outputDirectoryPath string) string {
+	return filepath.Join(outputDirectoryPath, cacheDirName, orphansFileName)
+}
+
+// upToDate reports whether handle's previously recorded render still matches
+// changed and contentHash, and its output files are still on disk, so
+// rendering it again can be skipped.
+func (cache *Cache) upToDate(outputDirectoryPath string, handle ancestry.Handle, changed int, contentHash string) bool {
+	entry, ok := cache.entries[handle]
+	if !ok || entry.Changed != changed || entry.ContentHash != contentHash {
+		return false
+	}
+	for _, outputPath := range entry.OutputPaths {
+		if _, err := os.Stat(filepath.Join(outputDirectoryPath, outputPath)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (cache *Cache) record(handle ancestry.Handle, changed int, contentHash string, outputPaths []string) {
+	cache.entries[handle] = cacheEntry{Changed: changed, ContentHash: contentHash, OutputPaths: outputPaths}
+}
+
+func (cache *Cache) forget(handle ancestry.Handle) {
+	delete(cache.entries, handle)
+}
+
+// orphans returns the output paths tracked in cache for handles that are no
+// longer present in liveHandles, so callers can prune the outputs of
+// entities that have since disappeared from the ancestry.
+func (cache *Cache) orphans(liveHandles map[ancestry.Handle]bool) []string {
+	var orphans []string
+	for handle, entry := range cache.entries {
+		if !liveHandles[handle] {
+			orphans = append(orphans, entry.OutputPaths...)
+		}
+	}
+	return orphans
+}
+
+// managedPaths returns every output-directory-relative file path Betty
+// considers its own: every path tracked in the cache, plus the fixed paths
+// Render always (re)writes regardless of caching.
+func (cache *Cache) managedPaths() map[string]bool {
+	paths := map[string]bool{
+		"sitemap.xml": true,
+		"atom.xml":    true,
+	}
+	paths[filepath.Join(cacheDirName, cacheFileName)] = true
+	paths[filepath.Join(cacheDirName, orphansFileName)] = true
+	for _, typeName := range entityTypeNames {
+		paths[filepath.Join(typeName, "index.html")] = true
+	}
+	for _, entry := range cache.entries {
+		for _, outputPath := range entry.OutputPaths {
+			paths[outputPath] = true
+		}
+	}
+	return paths
+}
+
+// pruneOrphans deletes orphans' output files from outputDirectoryPath. It
+// must run before their cache entries are forgotten: once forgotten, a
+// left-behind file is no longer in managedPaths, and the next incremental
+// run's assertOutputDirectory would mistake it for foreign content.
+func pruneOrphans(outputDirectoryPath string, orphans []string) error {
+	for _, orphan := range orphans {
+		if err := os.Remove(filepath.Join(outputDirectoryPath, orphan)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOrphanManifest writes the list of orphaned output paths to
+// <output>/.betty/orphans.json, as a record of what pruneOrphans removed.
+func writeOrphanManifest(outputDirectoryPath string, orphans []string) error {
+	bytes, err := json.MarshalIndent(orphans, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := orphansPath(outputDirectoryPath)
+	if err := CreateDirectory(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return os.WriteFile(path, bytes, 0640)
+}
+
+// contentHash hashes a template's inputs, so a cached render can be
+// invalidated when its view model changes even though the entity's own
+// Changed timestamp did not, e.g. because SiteTitle changed.
+func contentHash(typeName string, viewModel interface{}) (string, error) {
+	serialized, err := json.Marshal(viewModel)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%s", typeName, templateVersion, serialized)))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,112 @@
+package render
+
+import (
+	"encoding/xml"
+	"github.com/bartfeenstra/betty/ancestry"
+	"path/filepath"
+	"sort"
+)
+
+// recentEntityCount is the number of most recently changed entities included
+// in atom.xml.
+const recentEntityCount = 20
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	URLs    []sitemapURL
+}
+
+type sitemapURL struct {
+	XMLName    xml.Name `xml:"url"`
+	Loc        string   `xml:"loc"`
+	LastMod    string   `xml:"lastmod,omitempty"`
+	ChangeFreq string   `xml:"changefreq,omitempty"`
+}
+
+type feed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []feedEntry `xml:"entry"`
+}
+
+type feedEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    feedLink `xml:"link"`
+}
+
+type feedLink struct {
+	Href string `xml:"href,attr"`
+}
+
+// renderSitemap writes a sitemap.xml at the output root listing every
+// entity's URL.
+func renderSitemap(outputDirectoryPath string, entities []ancestry.Entity, options Options) error {
+	urls := make([]sitemapURL, 0, len(entities))
+	for _, entity := range entities {
+		urls = append(urls, sitemapURL{
+			Loc:        options.BaseURL + URLFor(entity),
+			LastMod:    unixToISO8601(entity.GetChanged()),
+			ChangeFreq: "monthly",
+		})
+	}
+	return writeXML(filepath.Join(outputDirectoryPath, "sitemap.xml"), urlSet{
+		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
+		URLs:  urls,
+	})
+}
+
+// renderFeed writes an atom.xml feed of the most recently changed entities.
+func renderFeed(outputDirectoryPath string, entities []ancestry.Entity, options Options) error {
+	sorted := make([]ancestry.Entity, len(entities))
+	copy(sorted, entities)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GetChanged() > sorted[j].GetChanged()
+	})
+	if len(sorted) > recentEntityCount {
+		sorted = sorted[:recentEntityCount]
+	}
+
+	entries := make([]feedEntry, 0, len(sorted))
+	var updated string
+	for i, entity := range sorted {
+		entityUpdated := unixToISO8601(entity.GetChanged())
+		if i == 0 {
+			updated = entityUpdated
+		}
+		url := options.BaseURL + URLFor(entity)
+		entries = append(entries, feedEntry{
+			ID:      url,
+			Title:   entity.GetLabel(),
+			Updated: entityUpdated,
+			Link:    feedLink{Href: url},
+		})
+	}
+
+	return writeXML(filepath.Join(outputDirectoryPath, "atom.xml"), feed{
+		ID:      options.BaseURL + "/",
+		Title:   options.SiteTitle,
+		Updated: updated,
+		Entries: entries,
+	})
+}
+
+func writeXML(filePath string, v interface{}) error {
+	f, err := createFileAt(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(f)
+	encoder.Indent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,42 @@
+package render
+
+import "github.com/bartfeenstra/betty/privacy"
+
+// Mode controls how Render treats a pre-existing output directory.
+type Mode int
+
+const (
+	// ModeFresh requires the output directory to be empty, as Render always
+	// has.
+	ModeFresh Mode = iota
+	// ModeIncremental allows a pre-existing output directory, as long as it
+	// contains only files Betty itself manages, tracked in its Cache. Render
+	// then skips re-rendering entities whose data and templates haven't
+	// changed.
+	ModeIncremental
+)
+
+// Options configures how an ancestry is rendered.
+type Options struct {
+	// ThemePath is the path to a directory of HTML templates to render with.
+	// When empty, the default, embedded theme is used.
+	ThemePath string
+	// BaseURL is the public URL the rendered site will be served from, e.g.
+	// "https://example.com".
+	BaseURL string
+	// SiteTitle is shown throughout the rendered site, e.g. in the layout's
+	// header and in page titles.
+	SiteTitle string
+	// Mode controls how a pre-existing output directory is treated.
+	Mode Mode
+	// Privacy, when set, omits or redacts entities it considers private.
+	// When nil, privacy filtering is disabled and every entity is rendered.
+	Privacy *privacy.Policy
+}
+
+func (options Options) loadTheme() (*Theme, error) {
+	if options.ThemePath == "" {
+		return DefaultTheme()
+	}
+	return LoadTheme(options.ThemePath)
+}
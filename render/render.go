@@ -2,10 +2,12 @@ package render
 
 import (
 	"fmt"
-	"github.com/bartfeenstra/betty/gramps"
+	"github.com/bartfeenstra/betty/ancestry"
+	"github.com/bartfeenstra/betty/privacy"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 type DirectoryNotEmpty struct {
@@ -33,102 +35,439 @@ func AssertDirectoryIsEmpty(directoryPath string) error {
 	return nil
 }
 
+// entityTypeNames are the entity types Render produces output for.
+var entityTypeNames = []string{"person", "event", "place", "family"}
+
+// assertOutputDirectory checks outputDirectoryPath is usable for the given
+// Mode: ModeFresh requires it to be empty, while ModeIncremental allows a
+// pre-existing directory as long as every file in it is one Betty itself
+// manages, per cache.
+func assertOutputDirectory(outputDirectoryPath string, mode Mode, cache *Cache) error {
+	if mode == ModeFresh {
+		return AssertDirectoryIsEmpty(outputDirectoryPath)
+	}
+
+	err := CreateDirectory(outputDirectoryPath)
+	if err != nil {
+		return err
+	}
+	managed := cache.managedPaths()
+	return filepath.Walk(outputDirectoryPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(outputDirectoryPath, path)
+		if err != nil {
+			return err
+		}
+		if !managed[relativePath] {
+			return DirectoryNotEmpty{DirectoryPath: outputDirectoryPath}
+		}
+		return nil
+	})
+}
+
 func CreateDirectory(directoryPath string) error {
 	return os.MkdirAll(directoryPath, 0740)
 }
 
 func CreateFile(directoryPath string) (*os.File, error) {
-	err := CreateDirectory(directoryPath)
+	return createFileAt(filepath.Join(directoryPath, "index.html"))
+}
+
+// createFileAt creates filePath, including any missing parent directories.
+func createFileAt(filePath string) (*os.File, error) {
+	err := CreateDirectory(filepath.Dir(filePath))
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Create(filepath.Join(directoryPath, "index.html"))
+	f, err := os.Create(filePath)
 	if err != nil {
 		return nil, err
 	}
 	return f, nil
 }
 
-func Render(ancestry *gramps.Ancestry, outputDirectoryPath string) error {
-	err := AssertDirectoryIsEmpty(outputDirectoryPath)
+// unixToISO8601 formats a Gramps Unix change timestamp as the ISO 8601
+// timestamp sitemap.xml and atom.xml require.
+func unixToISO8601(unix int) string {
+	return time.Unix(int64(unix), 0).UTC().Format(time.RFC3339)
+}
+
+// page is the view model shared by every page the default theme's layout
+// renders.
+type page struct {
+	SiteTitle string
+	BaseURL   string
+	Title     string
+}
+
+// listItem is a single entry on an entity-type listing page.
+type listItem struct {
+	Label string
+	URL   string
+}
+
+func newPage(options Options, title string) page {
+	return page{
+		SiteTitle: options.SiteTitle,
+		BaseURL:   options.BaseURL,
+		Title:     title,
+	}
+}
+
+func Render(anc *ancestry.Ancestry, outputDirectoryPath string, options Options) error {
+	cache, err := loadCache(outputDirectoryPath)
+	if err != nil {
+		return err
+	}
+	err = assertOutputDirectory(outputDirectoryPath, options.Mode, cache)
+	if err != nil {
+		return err
+	}
+	theme, err := options.loadTheme()
 	if err != nil {
 		return err
 	}
-	for _, person := range ancestry.People {
-		err := RenderPerson(outputDirectoryPath, &person)
+
+	private := map[ancestry.Handle]bool{}
+	redact := false
+	if options.Privacy != nil {
+		private = privacy.Decide(*options.Privacy, anc)
+		redact = options.Privacy.Redact
+	}
+	index := buildIndex(allEntities(anc), private, redact)
+
+	liveHandles := map[ancestry.Handle]bool{}
+	for _, person := range anc.People {
+		if private[person.GetHandle()] && !redact {
+			continue
+		}
+		liveHandles[person.GetHandle()] = true
+		if private[person.GetHandle()] {
+			err = renderPrivateStub(outputDirectoryPath, "person", person.GetId(), person.GetHandle(), person.GetChanged(), theme, options, cache)
+		} else {
+			err = RenderPerson(outputDirectoryPath, &person, theme, options, cache, index, private)
+		}
 		if err != nil {
 			return err
 		}
 	}
-	for _, event := range ancestry.Events {
-		err := RenderEvent(outputDirectoryPath, &event)
+	err = renderList(outputDirectoryPath, "person", anc.People, theme, options, private, redact)
+	if err != nil {
+		return err
+	}
+	for _, event := range anc.Events {
+		if private[event.GetHandle()] && !redact {
+			continue
+		}
+		liveHandles[event.GetHandle()] = true
+		if private[event.GetHandle()] {
+			err = renderPrivateStub(outputDirectoryPath, "event", event.GetId(), event.GetHandle(), event.GetChanged(), theme, options, cache)
+		} else {
+			err = RenderEvent(outputDirectoryPath, &event, theme, options, cache, index, private)
+		}
 		if err != nil {
 			return err
 		}
 	}
-	for _, place := range ancestry.Places {
-		err := RenderPlace(outputDirectoryPath, &place)
+	err = renderList(outputDirectoryPath, "event", anc.Events, theme, options, private, redact)
+	if err != nil {
+		return err
+	}
+	for _, place := range anc.Places {
+		if private[place.GetHandle()] && !redact {
+			continue
+		}
+		liveHandles[place.GetHandle()] = true
+		if private[place.GetHandle()] {
+			err = renderPrivateStub(outputDirectoryPath, "place", place.GetId(), place.GetHandle(), place.GetChanged(), theme, options, cache)
+		} else {
+			err = RenderPlace(outputDirectoryPath, &place, theme, options, cache)
+		}
 		if err != nil {
 			return err
 		}
 	}
-	for _, family := range ancestry.Families {
-		err := RenderFamily(outputDirectoryPath, &family)
+	err = renderList(outputDirectoryPath, "place", anc.Places, theme, options, private, redact)
+	if err != nil {
+		return err
+	}
+	for _, family := range anc.Families {
+		if private[family.GetHandle()] && !redact {
+			continue
+		}
+		liveHandles[family.GetHandle()] = true
+		if private[family.GetHandle()] {
+			err = renderPrivateStub(outputDirectoryPath, "family", family.GetId(), family.GetHandle(), family.GetChanged(), theme, options, cache)
+		} else {
+			err = RenderFamily(outputDirectoryPath, &family, theme, options, cache, index, private)
+		}
 		if err != nil {
 			return err
 		}
 	}
-	return nil
-}
+	err = renderList(outputDirectoryPath, "family", anc.Families, theme, options, private, redact)
+	if err != nil {
+		return err
+	}
 
-func RenderPerson(outputDirectoryPath string, person *gramps.Person) error {
-	f, err := CreateFile(filepath.Join(outputDirectoryPath, "person", person.ID))
+	public := publicEntities(allEntities(anc), private)
+	err = renderSitemap(outputDirectoryPath, public, options)
 	if err != nil {
 		return err
 	}
-	_, err = f.WriteString(fmt.Sprintf("%s, %s", person.FamilyName, person.IndividualName))
+	err = renderFeed(outputDirectoryPath, public, options)
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func RenderEvent(outputDirectoryPath string, event *gramps.Event) error {
-	f, err := CreateFile(filepath.Join(outputDirectoryPath, "event", event.ID))
+	orphans := cache.orphans(liveHandles)
+	err = writeOrphanManifest(outputDirectoryPath, orphans)
 	if err != nil {
 		return err
 	}
-	_, err = f.WriteString(event.ID)
+	err = pruneOrphans(outputDirectoryPath, orphans)
 	if err != nil {
 		return err
 	}
+	for handle := range cache.entries {
+		if !liveHandles[handle] {
+			cache.forget(handle)
+		}
+	}
+	return cache.save(outputDirectoryPath)
+}
 
-	return nil
+// publicEntities filters entities down to those not marked private, so the
+// sitemap and feed never advertise privacy-protected content.
+func publicEntities(entities []ancestry.Entity, private map[ancestry.Handle]bool) []ancestry.Entity {
+	public := make([]ancestry.Entity, 0, len(entities))
+	for _, entity := range entities {
+		if !private[entity.GetHandle()] {
+			public = append(public, entity)
+		}
+	}
+	return public
+}
+
+// URLFor returns the path entity is rendered at, e.g. "/person/I1/".
+func URLFor(entity ancestry.Entity) string {
+	return "/" + entity.GetTypeName() + "/" + entity.GetId() + "/"
+}
+
+// buildIndex resolves entities by handle, for RenderX to turn the Handle
+// references on a Person, Family, or Event into hyperlinks. Entities that
+// are private and not being redacted are left out, since they have no page
+// to link to.
+func buildIndex(entities []ancestry.Entity, private map[ancestry.Handle]bool, redact bool) map[ancestry.Handle]ancestry.Entity {
+	index := make(map[ancestry.Handle]ancestry.Entity, len(entities))
+	for _, entity := range entities {
+		if private[entity.GetHandle()] && !redact {
+			continue
+		}
+		index[entity.GetHandle()] = entity
+	}
+	return index
+}
+
+// resolveLink turns handle into a listItem pointing at the entity it
+// references, or nil if handle is nil or does not resolve to a rendered
+// entity. A private entity's label is replaced with "Private".
+func resolveLink(index map[ancestry.Handle]ancestry.Entity, private map[ancestry.Handle]bool, handle *ancestry.Handle) *listItem {
+	if handle == nil {
+		return nil
+	}
+	entity, ok := index[*handle]
+	if !ok {
+		return nil
+	}
+	label := entity.GetLabel()
+	if private[entity.GetHandle()] {
+		label = "Private"
+	}
+	return &listItem{label, URLFor(entity)}
+}
+
+// resolveLinks is resolveLink for a slice of handles, omitting any that
+// don't resolve to a rendered entity.
+func resolveLinks(index map[ancestry.Handle]ancestry.Entity, private map[ancestry.Handle]bool, handles []ancestry.Handle) []listItem {
+	items := make([]listItem, 0, len(handles))
+	for _, handle := range handles {
+		if item := resolveLink(index, private, &handle); item != nil {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+// allEntities flattens an ancestry's entities into a single slice, for
+// subsystems such as the sitemap and feed that operate across entity types.
+func allEntities(anc *ancestry.Ancestry) []ancestry.Entity {
+	entities := make([]ancestry.Entity, 0, len(anc.People)+len(anc.Events)+len(anc.Places)+len(anc.Families))
+	for _, person := range anc.People {
+		entities = append(entities, person)
+	}
+	for _, event := range anc.Events {
+		entities = append(entities, event)
+	}
+	for _, place := range anc.Places {
+		entities = append(entities, place)
+	}
+	for _, family := range anc.Families {
+		entities = append(entities, family)
+	}
+	return entities
+}
+
+func RenderPerson(outputDirectoryPath string, person *ancestry.Person, theme *Theme, options Options, cache *Cache, index map[ancestry.Handle]ancestry.Entity, private map[ancestry.Handle]bool) error {
+	data := struct {
+		page
+		Person   *ancestry.Person
+		Families []listItem
+	}{newPage(options, person.GetLabel()), person, resolveLinks(index, private, person.FamiliesAsParent)}
+	return renderEntity(outputDirectoryPath, "person", "person.html", person.GetId(), person.GetHandle(), person.GetChanged(), data, theme, cache)
+}
+
+func RenderEvent(outputDirectoryPath string, event *ancestry.Event, theme *Theme, options Options, cache *Cache, index map[ancestry.Handle]ancestry.Entity, private map[ancestry.Handle]bool) error {
+	data := struct {
+		page
+		Event *ancestry.Event
+		Place *listItem
+	}{newPage(options, event.GetLabel()), event, resolveLink(index, private, event.Place)}
+	return renderEntity(outputDirectoryPath, "event", "event.html", event.GetId(), event.GetHandle(), event.GetChanged(), data, theme, cache)
+}
+
+func RenderPlace(outputDirectoryPath string, place *ancestry.Place, theme *Theme, options Options, cache *Cache) error {
+	data := struct {
+		page
+		Place *ancestry.Place
+	}{newPage(options, place.GetLabel()), place}
+	return renderEntity(outputDirectoryPath, "place", "place.html", place.GetId(), place.GetHandle(), place.GetChanged(), data, theme, cache)
 }
 
-func RenderPlace(outputDirectoryPath string, place *gramps.Place) error {
-	f, err := CreateFile(filepath.Join(outputDirectoryPath, "place", place.ID))
+func RenderFamily(outputDirectoryPath string, family *ancestry.Family, theme *Theme, options Options, cache *Cache, index map[ancestry.Handle]ancestry.Entity, private map[ancestry.Handle]bool) error {
+	data := struct {
+		page
+		Family   *ancestry.Family
+		Father   *listItem
+		Mother   *listItem
+		Children []listItem
+	}{newPage(options, family.GetLabel()), family, resolveLink(index, private, family.Father), resolveLink(index, private, family.Mother), resolveLinks(index, private, family.Children)}
+	return renderEntity(outputDirectoryPath, "family", "family.html", family.GetId(), family.GetHandle(), family.GetChanged(), data, theme, cache)
+}
+
+// renderPrivateStub renders a redacted stand-in for a private entity, at the
+// same <output>/<typeName>/<id>/index.html path its real page would use, so
+// the entity's URL stays stable regardless of its privacy verdict.
+func renderPrivateStub(outputDirectoryPath string, typeName string, id string, handle ancestry.Handle, changed int, theme *Theme, options Options, cache *Cache) error {
+	data := struct {
+		page
+	}{newPage(options, "Private")}
+	return renderEntity(outputDirectoryPath, typeName, "private.html", id, handle, changed, data, theme, cache)
+}
+
+// renderEntity executes templateName for data and writes it to
+// <output>/<typeName>/<id>/index.html, unless cache shows that output is
+// already up to date.
+func renderEntity(outputDirectoryPath string, typeName string, templateName string, id string, handle ancestry.Handle, changed int, data interface{}, theme *Theme, cache *Cache) error {
+	hash, err := contentHash(templateName, data)
 	if err != nil {
 		return err
 	}
-	_, err = f.WriteString(place.ID)
+	relativePath := filepath.Join(typeName, id, "index.html")
+	if cache.upToDate(outputDirectoryPath, handle, changed, hash) {
+		cache.record(handle, changed, hash, []string{relativePath})
+		return nil
+	}
+
+	f, err := createFileAt(filepath.Join(outputDirectoryPath, relativePath))
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
+	err = theme.executePage(f, templateName, data)
+	if err != nil {
+		return err
+	}
+	cache.record(handle, changed, hash, []string{relativePath})
 	return nil
 }
 
-func RenderFamily(outputDirectoryPath string, family *gramps.Family) error {
-	f, err := CreateFile(filepath.Join(outputDirectoryPath, "family", family.ID))
+// renderList renders the index.html listing page for a single entity type,
+// e.g. all persons under /person/. Private entities are omitted, unless
+// options.Privacy redacts them, in which case they are listed as "Private".
+func renderList(outputDirectoryPath string, typeName string, entities interface{}, theme *Theme, options Options, private map[ancestry.Handle]bool, redact bool) error {
+	items, err := listItems(entities, private, redact)
 	if err != nil {
 		return err
 	}
-	_, err = f.WriteString(family.ID)
+	f, err := CreateFile(filepath.Join(outputDirectoryPath, typeName))
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	return nil
+	data := struct {
+		page
+		Items []listItem
+	}{newPage(options, typeName), items}
+	return theme.executePage(f, "list.html", data)
+}
+
+func listItems(entities interface{}, private map[ancestry.Handle]bool, redact bool) ([]listItem, error) {
+	switch typed := entities.(type) {
+	case []ancestry.Person:
+		items := make([]listItem, 0, len(typed))
+		for _, entity := range typed {
+			if item, ok := listItemFor(entity, private, redact); ok {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case []ancestry.Event:
+		items := make([]listItem, 0, len(typed))
+		for _, entity := range typed {
+			if item, ok := listItemFor(entity, private, redact); ok {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case []ancestry.Place:
+		items := make([]listItem, 0, len(typed))
+		for _, entity := range typed {
+			if item, ok := listItemFor(entity, private, redact); ok {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case []ancestry.Family:
+		items := make([]listItem, 0, len(typed))
+		for _, entity := range typed {
+			if item, ok := listItemFor(entity, private, redact); ok {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("render: unsupported entity slice type %T", entities)
+	}
+}
+
+// listItemFor builds entity's listing entry, or reports ok=false when it
+// should be omitted: a private entity is omitted unless redact is set, in
+// which case it is listed with its label replaced by "Private".
+func listItemFor(entity ancestry.Entity, private map[ancestry.Handle]bool, redact bool) (listItem, bool) {
+	url := URLFor(entity)
+	if private[entity.GetHandle()] {
+		if !redact {
+			return listItem{}, false
+		}
+		return listItem{"Private", url}, true
+	}
+	return listItem{entity.GetLabel(), url}, true
 }
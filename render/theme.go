@@ -0,0 +1,76 @@
+package render
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+)
+
+//go:embed theme/default
+var defaultThemeFS embed.FS
+
+// layoutTemplateName is the file that wraps every page in the shared HTML
+// skeleton, via "{{template "content" .}}".
+const layoutTemplateName = "layout.html"
+
+// Theme is a set of HTML templates used to render an ancestry. Every page
+// file defines a "content" template of its own, so each is parsed into its
+// own *template.Template alongside layout.html rather than sharing one
+// template set, where the same "content" name would collide across files.
+type Theme struct {
+	templates map[string]*template.Template
+}
+
+// NewTheme builds a Theme from the HTML templates found at the root of fsys.
+// fsys must contain layout.html plus one file per page, each defining its own
+// "content" template for layout.html to render.
+func NewTheme(fsys fs.FS) (*Theme, error) {
+	pages, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		if page == layoutTemplateName {
+			continue
+		}
+		tmpl, err := template.ParseFS(fsys, layoutTemplateName, page)
+		if err != nil {
+			return nil, err
+		}
+		templates[page] = tmpl
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("render: no page templates found alongside %s", layoutTemplateName)
+	}
+	return &Theme{templates: templates}, nil
+}
+
+// executePage renders the named page template (layout.html plus that page's
+// "content" block) for data, writing the result to w.
+func (theme *Theme) executePage(w io.Writer, pageName string, data interface{}) error {
+	tmpl, ok := theme.templates[pageName]
+	if !ok {
+		return fmt.Errorf("render: theme has no page template %q", pageName)
+	}
+	return tmpl.ExecuteTemplate(w, layoutTemplateName, data)
+}
+
+// DefaultTheme returns the theme Betty ships with, so rendering works out of
+// the box without any flags.
+func DefaultTheme() (*Theme, error) {
+	themeFS, err := fs.Sub(defaultThemeFS, "theme/default")
+	if err != nil {
+		return nil, err
+	}
+	return NewTheme(themeFS)
+}
+
+// LoadTheme builds a Theme from the HTML templates found in the directory at
+// themePath.
+func LoadTheme(themePath string) (*Theme, error) {
+	return NewTheme(os.DirFS(themePath))
+}